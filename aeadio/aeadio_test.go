@@ -0,0 +1,255 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package aeadio
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "crypto/aes"
+import "crypto/cipher"
+import "testing"
+import "testing/iotest"
+
+const testChunkSize = 64
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return aead
+}
+
+func encrypt(t *testing.T, aead cipher.AEAD, input []byte) []byte {
+	var buf bytes.Buffer
+	wr, err := NewWriter(&buf, aead, testChunkSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := wr.Write(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(t *testing.T, aead cipher.AEAD, input []byte) ([]byte, error) {
+	rd, err := NewReader(bytes.NewReader(input), aead, testChunkSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ioutil.ReadAll(rd)
+}
+
+func TestNewWriter(t *testing.T) {
+	aead := newTestAEAD(t)
+	var vectors = []struct {
+		chunkSize int
+		err       error
+	}{
+		{chunkSize: -1, err: ErrInvalidSize},
+		{chunkSize: 0, err: ErrInvalidSize},
+		{chunkSize: 1, err: nil},
+		{chunkSize: MaxChunkSize, err: nil},
+		{chunkSize: MaxChunkSize + 1, err: ErrInvalidSize},
+	}
+
+	for i, v := range vectors {
+		if _, err := NewWriter(ioutil.Discard, aead, v.chunkSize); err != v.err {
+			t.Errorf("test %d, writer error mismatch: got %v, want %v", i, err, v.err)
+		}
+		if _, err := NewReader(bytes.NewReader(nil), aead, v.chunkSize); err != v.err {
+			t.Errorf("test %d, reader error mismatch: got %v, want %v", i, err, v.err)
+		}
+	}
+
+	// The nonce must have room for the chunk counter and last chunk flag.
+	block, _ := aes.NewCipher(make([]byte, 16))
+	short, err := cipher.NewGCMWithNonceSize(block, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewWriter(ioutil.Discard, short, testChunkSize); err != ErrNonceSize {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrNonceSize)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	aead := newTestAEAD(t)
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sizes = []int{
+		0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1,
+		3 * testChunkSize, 3*testChunkSize + 7, len(input),
+	}
+	for i, n := range sizes {
+		// Write the input in small pieces to exercise chunk buffering.
+		var buf bytes.Buffer
+		wr, err := NewWriter(&buf, aead, testChunkSize)
+		if err != nil {
+			t.Fatalf("test %d, unexpected error: %v", i, err)
+		}
+		for rem := input[:n]; len(rem) > 0; {
+			cnt := 13
+			if cnt > len(rem) {
+				cnt = len(rem)
+			}
+			if _, err := wr.Write(rem[:cnt]); err != nil {
+				t.Fatalf("test %d, unexpected error: %v", i, err)
+			}
+			rem = rem[cnt:]
+		}
+		if err := wr.Close(); err != nil {
+			t.Fatalf("test %d, unexpected error: %v", i, err)
+		}
+
+		chunks := n/testChunkSize + 1
+		if n > 0 && n%testChunkSize == 0 {
+			chunks-- // A full last chunk is not followed by an empty one
+		}
+		if got, want := buf.Len(), n+chunks*aead.Overhead(); got != want || wr.OutputOffset != int64(want) {
+			t.Errorf("test %d, output size mismatch: got %d, want %d", i, got, want)
+		}
+		if wr.InputOffset != int64(n) {
+			t.Errorf("test %d, input offset mismatch: got %d, want %d", i, wr.InputOffset, n)
+		}
+
+		rd, err := NewReader(iotest.OneByteReader(&buf), aead, testChunkSize)
+		if err != nil {
+			t.Fatalf("test %d, unexpected error: %v", i, err)
+		}
+		output, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Errorf("test %d, unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(output, input[:n]) {
+			t.Errorf("test %d, output data mismatch", i)
+		}
+		if rd.OutputOffset != int64(n) {
+			t.Errorf("test %d, output offset mismatch: got %d, want %d", i, rd.OutputOffset, n)
+		}
+		if err := rd.Close(); err != nil {
+			t.Errorf("test %d, unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	aead := newTestAEAD(t)
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	input = input[:4*testChunkSize+10]
+	stream := encrypt(t, aead, input)
+	encSize := testChunkSize + aead.Overhead()
+
+	swap := func(b []byte, i, j int) []byte {
+		b = append([]byte(nil), b...)
+		ci := append([]byte(nil), b[i*encSize:(i+1)*encSize]...)
+		copy(b[i*encSize:], b[j*encSize:(j+1)*encSize])
+		copy(b[j*encSize:], ci)
+		return b
+	}
+	flip := func(b []byte, i int) []byte {
+		b = append([]byte(nil), b...)
+		b[i] ^= 0x80
+		return b
+	}
+
+	var vectors = []struct {
+		desc   string // Description of the test
+		input  []byte // Corrupted ciphertext
+		output []byte // Expected authenticated output before the error
+	}{{
+		desc:   "empty stream",
+		input:  nil,
+		output: nil,
+	}, {
+		desc:   "tampered first chunk",
+		input:  flip(stream, 0),
+		output: nil,
+	}, {
+		desc:   "tampered tag of second chunk",
+		input:  flip(stream, 2*encSize-1),
+		output: input[:testChunkSize],
+	}, {
+		desc:   "tampered last chunk",
+		input:  flip(stream, len(stream)-1),
+		output: input[:4*testChunkSize],
+	}, {
+		desc:   "reordered chunks",
+		input:  swap(stream, 1, 2),
+		output: input[:testChunkSize],
+	}, {
+		desc:   "duplicated chunk",
+		input:  append(append([]byte(nil), stream[:encSize]...), stream...),
+		output: input[:testChunkSize],
+	}, {
+		desc:   "dropped chunk",
+		input:  append(append([]byte(nil), stream[:encSize]...), stream[2*encSize:]...),
+		output: input[:testChunkSize],
+	}, {
+		desc:   "truncated at chunk boundary",
+		input:  stream[:4*encSize],
+		output: input[:3*testChunkSize],
+	}, {
+		desc:   "truncated mid-chunk",
+		input:  stream[:2*encSize+5],
+		output: input[:2*testChunkSize],
+	}, {
+		desc:   "truncated within tag",
+		input:  stream[:len(stream)-1],
+		output: input[:4*testChunkSize],
+	}, {
+		desc:   "extended with trailing data",
+		input:  append(append([]byte(nil), stream...), 0),
+		output: input[:4*testChunkSize],
+	}, {
+		desc:   "extended with another stream",
+		input:  append(append([]byte(nil), stream...), stream...),
+		output: input[:4*testChunkSize],
+	}}
+
+	for i, v := range vectors {
+		output, err := decrypt(t, aead, v.input)
+		if err != ErrCorrupt {
+			t.Errorf("test %d, %s\nerror mismatch: got %v, want %v", i, v.desc, err, ErrCorrupt)
+		}
+		if !bytes.Equal(output, v.output) {
+			t.Errorf("test %d, %s\noutput mismatch: got %d bytes, want %d bytes", i, v.desc, len(output), len(v.output))
+		}
+	}
+}
+
+func TestWriterClose(t *testing.T) {
+	aead := newTestAEAD(t)
+	var buf bytes.Buffer
+	wr, err := NewWriter(&buf, aead, testChunkSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := wr.Write([]byte("x")); err != io.ErrClosedPipe {
+		t.Errorf("error mismatch: got %v, want %v", err, io.ErrClosedPipe)
+	}
+	if got, want := buf.Len(), aead.Overhead(); got != want {
+		t.Errorf("output size mismatch: got %d, want %d", got, want)
+	}
+}
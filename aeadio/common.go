@@ -0,0 +1,72 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Package aeadio implements chunked authenticated encryption of streams.
+//
+// The stream is split into chunks of a fixed plaintext size, each of which is
+// sealed independently with an AEAD using the STREAM construction described in
+// "Online Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance"
+// (Hoang, Reyhanitabar, Rogaway, and Vizár). The nonce for each chunk is a
+// big-endian chunk counter followed by a flag byte that marks the last chunk.
+// This detects tampered, reordered, dropped, and truncated chunks.
+//
+// Since the nonce sequence is fixed, a key must never be used for more than
+// one stream. Callers should derive a fresh key for each stream.
+//
+// This layer is intended to be applied after compression. Since all chunks
+// except the last hold exactly chunkSize bytes of plaintext, chunk i always
+// starts at ciphertext offset i*(chunkSize+Overhead()). Thus, a seek index
+// of uncompressed to compressed offsets remains usable: a compressed offset
+// identifies the single chunk that needs to be decrypted to start reading.
+package aeadio
+
+import "crypto/cipher"
+import "encoding/binary"
+
+// Error is the wrapper type for errors specific to this library.
+type Error string
+
+func (e Error) Error() string { return "aeadio: " + string(e) }
+
+var (
+	ErrCorrupt     error = Error("stream is corrupted")
+	ErrInvalidSize error = Error("chunk size must be in [1, MaxChunkSize]")
+	ErrNonceSize   error = Error("AEAD nonce size is too small")
+)
+
+const MaxChunkSize = 1 << 24
+
+const (
+	minNonceSize = 8 + 1 // Chunk counter followed by the last chunk flag
+	flagLast     = 0x01  // Flag value marking the last chunk
+)
+
+// chunkNonce tracks the nonce for the chunk currently being processed.
+type chunkNonce struct {
+	buf     []byte // The nonce; leading bytes beyond the counter are zero
+	counter uint64 // Index of the current chunk
+}
+
+// Next returns the nonce for the current chunk and advances the counter.
+// A 64-bit counter cannot realistically wrap, so this is not checked.
+func (cn *chunkNonce) Next(last bool) []byte {
+	n := len(cn.buf)
+	binary.BigEndian.PutUint64(cn.buf[n-minNonceSize:], cn.counter)
+	cn.buf[n-1] = 0
+	if last {
+		cn.buf[n-1] = flagLast
+	}
+	cn.counter++
+	return cn.buf
+}
+
+func checkParams(aead cipher.AEAD, chunkSize int) error {
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return ErrInvalidSize
+	}
+	if aead.NonceSize() < minNonceSize {
+		return ErrNonceSize
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package aeadio
+
+import "io"
+import "crypto/cipher"
+
+type Reader struct {
+	InputOffset  int64 // Total number of bytes read from underlying io.Reader
+	OutputOffset int64 // Total number of bytes emitted from Read
+
+	rd    io.Reader
+	aead  cipher.AEAD
+	nonce chunkNonce
+	buf   []byte // Room for one sealed chunk plus one byte of lookahead
+	end   int    // Valid ciphertext is buf[:end]
+	out   []byte // Authenticated plaintext not yet returned; aliases buf
+	last  bool   // Whether the last chunk has been opened
+	err   error  // Persistent error
+}
+
+// NewReader creates a Reader that decrypts a stream produced by a Writer
+// with the same AEAD and chunk size. Data is only returned after the chunk
+// containing it has been authenticated. A stream that was tampered with,
+// reordered, or truncated results in ErrCorrupt.
+func NewReader(r io.Reader, aead cipher.AEAD, chunkSize int) (*Reader, error) {
+	if err := checkParams(aead, chunkSize); err != nil {
+		return nil, err
+	}
+	return &Reader{
+		rd:    r,
+		aead:  aead,
+		nonce: chunkNonce{buf: make([]byte, aead.NonceSize())},
+		buf:   make([]byte, chunkSize+aead.Overhead()+1),
+	}, nil
+}
+
+func (ar *Reader) Read(buf []byte) (int, error) {
+	for len(ar.out) == 0 {
+		if ar.err != nil {
+			return 0, ar.err
+		}
+		ar.err = ar.next()
+	}
+	cnt := copy(buf, ar.out)
+	ar.out = ar.out[cnt:]
+	ar.OutputOffset += int64(cnt)
+	return cnt, nil
+}
+
+// Close ends the decryption stream. It does not close the underlying
+// io.Reader.
+func (ar *Reader) Close() error {
+	if ar.err == nil || ar.err == io.EOF || ar.err == io.ErrClosedPipe {
+		ar.out = nil // Make sure future reads fail
+		ar.err = io.ErrClosedPipe
+		return nil
+	}
+	return ar.err // Return the persistent error
+}
+
+// next reads and opens the next chunk. A chunk is the last one if and only if
+// the underlying reader ends before the lookahead byte following it.
+func (ar *Reader) next() error {
+	if ar.last {
+		return io.EOF
+	}
+
+	encSize := len(ar.buf) - 1
+	if ar.end > encSize {
+		ar.buf[0] = ar.buf[encSize] // Carry over the lookahead byte
+		ar.end = 1
+	}
+	cnt, err := io.ReadFull(ar.rd, ar.buf[ar.end:])
+	ar.end += cnt
+	ar.InputOffset += int64(cnt)
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		ar.last = true
+	default:
+		return err
+	}
+
+	chunk := ar.buf[:ar.end]
+	if !ar.last {
+		chunk = ar.buf[:encSize]
+	}
+	out, err := ar.aead.Open(chunk[:0], ar.nonce.Next(ar.last), chunk, nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+	ar.out = out
+	return nil
+}
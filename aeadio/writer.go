@@ -0,0 +1,85 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package aeadio
+
+import "io"
+import "crypto/cipher"
+
+type Writer struct {
+	InputOffset  int64 // Total number of bytes written to Writer
+	OutputOffset int64 // Total number of bytes written to underlying io.Writer
+
+	wr        io.Writer
+	aead      cipher.AEAD
+	nonce     chunkNonce
+	buf       []byte // Pending plaintext, with room to seal it in place
+	chunkSize int    // Plaintext size of all chunks except the last
+	err       error  // Persistent error
+}
+
+// NewWriter creates a Writer that encrypts data written to it in chunks of
+// chunkSize bytes and writes the sealed chunks to w. The AEAD must have a
+// nonce size of at least 9 bytes. The caller must call Close to emit the
+// final chunk, without which the stream will fail to authenticate.
+func NewWriter(w io.Writer, aead cipher.AEAD, chunkSize int) (*Writer, error) {
+	if err := checkParams(aead, chunkSize); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		wr:        w,
+		aead:      aead,
+		nonce:     chunkNonce{buf: make([]byte, aead.NonceSize())},
+		buf:       make([]byte, 0, chunkSize+aead.Overhead()),
+		chunkSize: chunkSize,
+	}, nil
+}
+
+func (aw *Writer) Write(buf []byte) (int, error) {
+	if aw.err != nil {
+		return 0, aw.err
+	}
+
+	var total int
+	for len(buf) > 0 {
+		// A full chunk is only sealed once more data arrives, since it may
+		// otherwise need to be marked as the last chunk by Close.
+		if len(aw.buf) == aw.chunkSize {
+			if aw.err = aw.flush(false); aw.err != nil {
+				return total, aw.err
+			}
+		}
+		cnt := copy(aw.buf[len(aw.buf):aw.chunkSize], buf)
+		aw.buf = aw.buf[:len(aw.buf)+cnt]
+		aw.InputOffset += int64(cnt)
+		total += cnt
+		buf = buf[cnt:]
+	}
+	return total, nil
+}
+
+// Close seals and writes the last chunk. It does not close the underlying
+// io.Writer.
+func (aw *Writer) Close() error {
+	if aw.err == io.ErrClosedPipe {
+		return nil
+	}
+	if aw.err != nil {
+		return aw.err // Return the persistent error
+	}
+	if aw.err = aw.flush(true); aw.err != nil {
+		return aw.err
+	}
+	aw.err = io.ErrClosedPipe // Make sure future writes fail
+	return nil
+}
+
+// flush seals the pending plaintext as a single chunk and writes it out.
+func (aw *Writer) flush(last bool) error {
+	sealed := aw.aead.Seal(aw.buf[:0], aw.nonce.Next(last), aw.buf, nil)
+	aw.buf = aw.buf[:0]
+	cnt, err := aw.wr.Write(sealed)
+	aw.OutputOffset += int64(cnt)
+	return err
+}
@@ -0,0 +1,220 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Tool to compare the decompressed contents of two compressed files without
+// writing the decompressed data to temporary files.
+//
+// The format of each input is chosen by the -format flag, or otherwise detected
+// from its file extension (".fl" for DEFLATE and ".br" for Brotli). Since each
+// input is detected separately, a DEFLATE file may be compared to a Brotli file.
+//
+// The exit status is 0 if the decompressed contents are identical, 1 if they
+// differ, and 2 if an error occurred.
+//
+// Example usage:
+//
+//	$ ./cdiff -hexdump twain-best-1e4.fl digits-best-1e4.br
+//	twain-best-1e4.fl digits-best-1e4.br differ: offset 0
+//	twain-best-1e4.fl:
+//		00000000  54 68 65 20 50 72 6f 6a  65 63 74 20 47 75 74 65  |The Project Gute|
+//		...
+//	digits-best-1e4.br:
+//		00000000  32 2e 37 31 38 32 38 31  38 32 38 34 35 39 30 34  |2.71828182845904|
+//		...
+package main
+
+import "io"
+import "os"
+import "fmt"
+import "flag"
+import "bytes"
+import "strings"
+import "path/filepath"
+import "github.com/dsnet/compress/brotli"
+import "github.com/dsnet/compress/flate"
+
+const (
+	blockSize = 1 << 15 // Number of bytes compared at a time
+	dumpSize  = 64      // Number of bytes shown by the hexdump
+)
+
+var formats = map[string]func(io.Reader) io.Reader{
+	"flate":  func(r io.Reader) io.Reader { return flate.NewReader(r) },
+	"brotli": func(r io.Reader) io.Reader { return brotli.NewReader(r) },
+}
+
+var extensions = map[string]string{
+	".fl": "flate",
+	".br": "brotli",
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the tool and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cdiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "", "format of both inputs (flate|brotli); detected by file extension if empty")
+	hexdump := fs.Bool("hexdump", false, "print a hexdump of the first differing region")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: cdiff [flags] file1 file2\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	names := [2]string{fs.Arg(0), fs.Arg(1)}
+
+	var rds [2]io.Reader
+	for i, name := range names {
+		newReader, err := lookupFormat(name, *format)
+		if err != nil {
+			fmt.Fprintf(stderr, "cdiff: %v\n", err)
+			return 2
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "cdiff: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		rds[i] = newReader(f)
+	}
+
+	var bufs [2][]byte
+	var offset int64
+	for {
+		var eofs [2]bool
+		for i := range rds {
+			var err error
+			bufs[i], err = readBlock(rds[i], bufs[i][:0], blockSize)
+			if err == io.EOF {
+				eofs[i] = true
+			} else if err != nil {
+				fmt.Fprintf(stderr, "cdiff: %s: %v\n", names[i], err)
+				return 2
+			}
+		}
+
+		if n := mismatch(bufs[0], bufs[1]); n >= 0 {
+			if n == len(bufs[0]) || n == len(bufs[1]) {
+				short := 0
+				if n == len(bufs[1]) {
+					short = 1
+				}
+				fmt.Fprintf(stdout, "EOF on %s after %d bytes\n", names[short], offset+int64(n))
+			} else {
+				fmt.Fprintf(stdout, "%s %s differ: offset %d\n", names[0], names[1], offset+int64(n))
+			}
+			if *hexdump {
+				// Start the dump at a 16-byte boundary before the difference,
+				// reading a little more data to fill the dump if necessary.
+				start := n &^ 15
+				for i := range rds {
+					if len(bufs[i]) < start+dumpSize && !eofs[i] {
+						var err error
+						bufs[i], err = readBlock(rds[i], bufs[i], start+dumpSize-len(bufs[i]))
+						if err != nil && err != io.EOF {
+							fmt.Fprintf(stderr, "cdiff: %s: %v\n", names[i], err)
+							return 2
+						}
+					}
+					end := start + dumpSize
+					if end > len(bufs[i]) {
+						end = len(bufs[i])
+					}
+					fmt.Fprintf(stdout, "%s:\n", names[i])
+					dump(stdout, bufs[i][start:end], offset+int64(start))
+				}
+			}
+			return 1
+		}
+		offset += int64(len(bufs[0]))
+		if eofs[0] && eofs[1] {
+			fmt.Fprintf(stdout, "%s %s are identical: %d bytes\n", names[0], names[1], offset)
+			return 0
+		}
+	}
+}
+
+// lookupFormat returns the decompressor for the named file. The format is
+// detected from the file extension if not explicitly provided.
+func lookupFormat(name, format string) (func(io.Reader) io.Reader, error) {
+	if format == "" {
+		var ok bool
+		if format, ok = extensions[strings.ToLower(filepath.Ext(name))]; !ok {
+			return nil, fmt.Errorf("%s: unknown file extension; use -format", name)
+		}
+	}
+	newReader, ok := formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format: %q", format)
+	}
+	return newReader, nil
+}
+
+// readBlock appends up to n bytes read from r to buf. It returns io.EOF only if
+// the end of the stream was reached.
+func readBlock(r io.Reader, buf []byte, n int) ([]byte, error) {
+	if cap(buf)-len(buf) < n {
+		buf = append(make([]byte, 0, len(buf)+n), buf...)
+	}
+	cnt, err := io.ReadFull(r, buf[len(buf):len(buf)+n])
+	buf = buf[:len(buf)+cnt]
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return buf, err
+}
+
+// mismatch returns the index of the first difference between a and b,
+// or -1 if they are equal.
+func mismatch(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) < len(b) {
+		return len(a)
+	}
+	return len(b)
+}
+
+// dump writes a hexdump of buf, labeling each line starting at offset.
+func dump(w io.Writer, buf []byte, offset int64) {
+	for len(buf) > 0 {
+		line := buf
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		var hex, ascii bytes.Buffer
+		for i := 0; i < 16; i++ {
+			if i == 8 {
+				hex.WriteByte(' ')
+			}
+			if i >= len(line) {
+				hex.WriteString("   ")
+				continue
+			}
+			fmt.Fprintf(&hex, " %02x", line[i])
+			if c := line[i]; c >= 0x20 && c < 0x7f {
+				ascii.WriteByte(c)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		fmt.Fprintf(w, "\t%08x %s  |%s|\n", offset, hex.String(), ascii.String())
+		buf, offset = buf[len(line):], offset+int64(len(line))
+	}
+}
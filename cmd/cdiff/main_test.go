@@ -0,0 +1,112 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package main
+
+import "bytes"
+import "strings"
+import "testing"
+
+func TestRun(t *testing.T) {
+	const (
+		flDir = "../../flate/testdata/"
+		brDir = "../../brotli/testdata/"
+	)
+
+	var vectors = []struct {
+		desc   string   // Description of the test
+		args   []string // Command line arguments
+		status int      // Expected exit status
+		output []string // Expected substrings of the output
+	}{{
+		desc:   "identical flate files",
+		args:   []string{flDir + "digits-best-1e4.fl", flDir + "digits-speed-1e4.fl"},
+		status: 0,
+		output: []string{"are identical: 10000 bytes"},
+	}, {
+		desc:   "identical brotli files",
+		args:   []string{brDir + "twain-best-1e4.br", brDir + "twain-best-1e4.br"},
+		status: 0,
+		output: []string{"are identical: 10000 bytes"},
+	}, {
+		desc:   "identical flate and brotli files",
+		args:   []string{flDir + "twain-default-1e5.fl", brDir + "twain-default-1e5.br"},
+		status: 0,
+		output: []string{"are identical: 100000 bytes"},
+	}, {
+		desc:   "identical empty files",
+		args:   []string{brDir + "empty.br", brDir + "empty.00.br"},
+		status: 0,
+		output: []string{"are identical: 0 bytes"},
+	}, {
+		desc:   "different files",
+		args:   []string{flDir + "twain-best-1e4.fl", brDir + "digits-best-1e4.br"},
+		status: 1,
+		output: []string{"differ: offset 0"},
+	}, {
+		desc:   "different files with hexdump",
+		args:   []string{"-hexdump", flDir + "twain-best-1e4.fl", brDir + "digits-best-1e4.br"},
+		status: 1,
+		output: []string{
+			"differ: offset 0",
+			"00000000  54 68 65 20 50 72 6f 6a  65 63 74 20 47 75 74 65  |The Project Gute|",
+			"00000030  39 39 39 35 39 35 37 34  39 36 36 39 36 37 36 32  |9995957496696762|",
+		},
+	}, {
+		desc:   "prefix of another file",
+		args:   []string{"-hexdump", flDir + "twain-best-1e4.fl", flDir + "twain-best-1e5.fl"},
+		status: 1,
+		output: []string{
+			"EOF on " + flDir + "twain-best-1e4.fl after 10000 bytes",
+			"00002710  54 68 65 0a 6d 6f 72 65  20 54 6f 6d 20 73 74 61  |The.more Tom sta|",
+		},
+	}, {
+		desc:   "difference mid-stream",
+		args:   []string{"-hexdump", brDir + "quickfox_repeated.br", brDir + "quickfox.br"},
+		status: 1,
+		output: []string{
+			"EOF on " + brDir + "quickfox.br after 43 bytes",
+			"00000020  68 65 20 6c 61 7a 79 20  64 6f 67                 |he lazy dog|",
+		},
+	}, {
+		desc:   "explicit format",
+		args:   []string{"-format=brotli", brDir + "monkey.br", brDir + "monkey.br"},
+		status: 0,
+		output: []string{"are identical"},
+	}, {
+		desc:   "unknown extension",
+		args:   []string{brDir + "monkey", brDir + "monkey.br"},
+		status: 2,
+		output: []string{"unknown file extension"},
+	}, {
+		desc:   "unknown format",
+		args:   []string{"-format=lzma", brDir + "monkey.br", brDir + "monkey.br"},
+		status: 2,
+		output: []string{"unknown format"},
+	}, {
+		desc:   "corrupted input",
+		args:   []string{"-format=brotli", brDir + "monkey", brDir + "monkey.br"},
+		status: 2,
+		output: []string{"brotli: "},
+	}, {
+		desc:   "missing argument",
+		args:   []string{brDir + "monkey.br"},
+		status: 2,
+		output: []string{"Usage: cdiff"},
+	}}
+
+	for i, v := range vectors {
+		var stdout, stderr bytes.Buffer
+		status := run(v.args, &stdout, &stderr)
+		output := stdout.String() + stderr.String()
+		if status != v.status {
+			t.Errorf("test %d, %s\nexit status mismatch: got %d, want %d\n%s", i, v.desc, status, v.status, output)
+		}
+		for _, s := range v.output {
+			if !strings.Contains(output, s) {
+				t.Errorf("test %d, %s\noutput mismatch: got %q, want substring %q", i, v.desc, output, s)
+			}
+		}
+	}
+}
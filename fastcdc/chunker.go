@@ -0,0 +1,153 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Package fastcdc implements content-defined chunking using the FastCDC
+// algorithm, described in "FastCDC: a Fast and Efficient Content-Defined
+// Chunking Approach for Data Deduplication" (USENIX ATC 2016).
+//
+// Chunk boundaries depend only on the local content of the input, so inserting
+// or deleting data only affects the chunks near the edit. Compressing each
+// chunk independently thus produces output that deduplicates well.
+package fastcdc
+
+import "io"
+
+// Error is the wrapper type for errors specific to this library.
+type Error string
+
+func (e Error) Error() string { return "fastcdc: " + string(e) }
+
+var (
+	ErrInvalidSize error = Error("average size must be a power of two in [MinAvgSize, MaxAvgSize]")
+)
+
+const (
+	MinAvgSize = 1 << 6
+	MaxAvgSize = 1 << 22
+)
+
+// gearLUT maps each byte to a pseudo-random value for the gear rolling hash.
+// It must never change, otherwise chunk boundaries would not be stable across
+// versions of this package.
+var gearLUT [256]uint64
+
+func init() {
+	// Generate the table using SplitMix64 with a fixed seed.
+	var x uint64
+	for i := range gearLUT {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearLUT[i] = z ^ (z >> 31)
+	}
+}
+
+type Chunker struct {
+	Offset int64 // Total number of bytes returned as chunks
+
+	rd  io.Reader // Input source
+	buf []byte    // Lazily allocated room for two maximum sized chunks
+	pos int       // Have chunked buf[:pos] already
+	end int       // Valid data is buf[:end]
+	err error     // Persistent error
+
+	minSize int    // Chunks are never smaller than this, except the last
+	avgSize int    // Desired average chunk size
+	maxSize int    // Chunks are never larger than this
+	maskS   uint64 // Harder to match mask used before reaching avgSize
+	maskL   uint64 // Easier to match mask used after reaching avgSize
+}
+
+// NewChunker creates a Chunker that splits the data read from r into chunks
+// averaging roughly avgSize bytes. Chunks are between avgSize/4 and avgSize*8
+// bytes in size, except for the final chunk which may be smaller.
+func NewChunker(r io.Reader, avgSize int) (*Chunker, error) {
+	if avgSize < MinAvgSize || avgSize > MaxAvgSize || avgSize&(avgSize-1) != 0 {
+		return nil, ErrInvalidSize
+	}
+	nb := uint(0)
+	for 1<<nb < avgSize {
+		nb++
+	}
+
+	// Normalized chunking with level 2, as described in section 3.4.
+	c := &Chunker{
+		minSize: avgSize / 4,
+		avgSize: avgSize,
+		maxSize: avgSize * 8,
+		maskS:   ^uint64(0) << (64 - (nb + 2)),
+		maskL:   ^uint64(0) << (64 - (nb - 2)),
+	}
+	c.Reset(r)
+	return c, nil
+}
+
+// Next returns the next chunk of data. The returned slice is only valid until
+// the next call to Next or Reset. At the end of the input, it returns io.EOF.
+func (c *Chunker) Next() ([]byte, error) {
+	if c.buf == nil {
+		c.buf = make([]byte, 2*c.maxSize) // Lazy allocate
+	}
+	if c.end-c.pos < c.maxSize && c.err == nil {
+		c.end = copy(c.buf, c.buf[c.pos:c.end])
+		c.pos = 0
+		for c.end < len(c.buf) && c.err == nil {
+			var cnt int
+			cnt, c.err = c.rd.Read(c.buf[c.end:])
+			c.end += cnt
+		}
+	}
+	if c.err != nil && c.err != io.EOF {
+		return nil, c.err
+	}
+	if c.pos == c.end {
+		return nil, c.err // Must be io.EOF
+	}
+
+	cnt := c.cut(c.buf[c.pos:c.end])
+	chunk := c.buf[c.pos : c.pos+cnt]
+	c.pos += cnt
+	c.Offset += int64(cnt)
+	return chunk, nil
+}
+
+// Reset discards the Chunker's state and makes it equivalent to the result of
+// NewChunker with the same average size, but reading from r instead.
+func (c *Chunker) Reset(r io.Reader) {
+	c.Offset = 0
+	c.rd = r
+	c.pos, c.end = 0, 0
+	c.err = nil
+}
+
+// cut returns the length of the first chunk in buf.
+func (c *Chunker) cut(buf []byte) int {
+	if len(buf) <= c.minSize {
+		return len(buf)
+	}
+	if len(buf) > c.maxSize {
+		buf = buf[:c.maxSize]
+	}
+	mid := c.avgSize
+	if mid > len(buf) {
+		mid = len(buf)
+	}
+
+	var h uint64
+	i := c.minSize
+	for ; i < mid; i++ {
+		h = h<<1 + gearLUT[buf[i]]
+		if h&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < len(buf); i++ {
+		h = h<<1 + gearLUT[buf[i]]
+		if h&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return len(buf)
+}
@@ -0,0 +1,150 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package fastcdc
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "reflect"
+import "testing"
+import "testing/iotest"
+
+func chunkAll(t *testing.T, r io.Reader, avgSize int) (chunks []string) {
+	c, err := NewChunker(r, avgSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	var vectors = []struct {
+		avgSize int
+		err     error
+	}{
+		{avgSize: 0, err: ErrInvalidSize},
+		{avgSize: 32, err: ErrInvalidSize},
+		{avgSize: 64},
+		{avgSize: 100, err: ErrInvalidSize},
+		{avgSize: 8192},
+		{avgSize: 1 << 22},
+		{avgSize: 1 << 23, err: ErrInvalidSize},
+		{avgSize: int(^uint(0) >> 1), err: ErrInvalidSize},
+	}
+
+	for i, v := range vectors {
+		if _, err := NewChunker(nil, v.avgSize); err != v.err {
+			t.Errorf("test %d, error mismatch: got %v, want %v", i, err, v.err)
+		}
+	}
+}
+
+func TestChunker(t *testing.T) {
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, avgSize := range []int{64, 1024, 8192, 65536} {
+		chunks := chunkAll(t, bytes.NewReader(input), avgSize)
+		var output []byte
+		for i, chunk := range chunks {
+			if len(chunk) > avgSize*8 {
+				t.Errorf("avgSize %d, chunk %d: got length %d, want at most %d", avgSize, i, len(chunk), avgSize*8)
+			}
+			if len(chunk) < avgSize/4 && i < len(chunks)-1 {
+				t.Errorf("avgSize %d, chunk %d: got length %d, want at least %d", avgSize, i, len(chunk), avgSize/4)
+			}
+			output = append(output, chunk...)
+		}
+		if !bytes.Equal(output, input) {
+			t.Errorf("avgSize %d, concatenated chunks do not match input", avgSize)
+		}
+
+		// The average chunk size should be in the right ballpark.
+		if avg := len(input) / len(chunks); avg < avgSize/2 || avg > avgSize*2 {
+			t.Errorf("avgSize %d, got average chunk size %d", avgSize, avg)
+		}
+
+		// Boundaries must not depend on how the input is read.
+		got := chunkAll(t, iotest.OneByteReader(bytes.NewReader(input)), avgSize)
+		if !reflect.DeepEqual(got, chunks) {
+			t.Errorf("avgSize %d, chunks mismatch when using a one byte reader", avgSize)
+		}
+	}
+}
+
+func TestChunkerShift(t *testing.T) {
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inserting data at the start should only affect the first few chunks.
+	chunks1 := chunkAll(t, bytes.NewReader(input), 4096)
+	chunks2 := chunkAll(t, io.MultiReader(bytes.NewReader([]byte("prefix")), bytes.NewReader(input)), 4096)
+	seen := make(map[string]bool)
+	for _, chunk := range chunks1 {
+		seen[chunk] = true
+	}
+	var shared int
+	for _, chunk := range chunks2 {
+		if seen[chunk] {
+			shared++
+		}
+	}
+	if shared < len(chunks1)-2 {
+		t.Errorf("got %d shared chunks, want at least %d", shared, len(chunks1)-2)
+	}
+}
+
+func TestChunkerStable(t *testing.T) {
+	// These lengths must never change, otherwise data chunked by previous
+	// versions of this package will no longer deduplicate.
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{13899, 11605, 13969, 16735, 6031, 6030, 8275, 15160, 8472, 8944}
+
+	chunks := chunkAll(t, bytes.NewReader(input), 8192)
+	for i, n := range want {
+		if len(chunks[i]) != n {
+			t.Errorf("chunk %d: got length %d, want %d", i, len(chunks[i]), n)
+		}
+	}
+}
+
+func BenchmarkChunker(b *testing.B) {
+	input, err := ioutil.ReadFile("../testdata/twain.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, err := NewChunker(nil, 8192)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Reset(bytes.NewReader(input))
+		for {
+			if _, err := c.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
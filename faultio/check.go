@@ -0,0 +1,45 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package faultio
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "fmt"
+
+// CheckReader verifies that the decompressor returned by newReader properly
+// handles faults in its input. The input must be a valid compressed stream.
+//
+// It checks that short reads do not alter the decompressed output, and that an
+// error injected at every offset of the input consumed by the decompressor is
+// reported back to the caller unchanged. Since the input is decompressed once
+// per offset, it should be small.
+func CheckReader(newReader func(io.Reader) io.Reader, input []byte) error {
+	fr := &Reader{R: bytes.NewReader(input)}
+	want, err := ioutil.ReadAll(newReader(fr))
+	if err != nil {
+		return Error(fmt.Sprintf("unexpected error on valid input: %v", err))
+	}
+	consumed := fr.Offset
+
+	for _, n := range []int{1, 2, 3, 7} {
+		fr := &Reader{R: bytes.NewReader(input), MaxRead: n}
+		got, err := ioutil.ReadAll(newReader(fr))
+		if err != nil {
+			return Error(fmt.Sprintf("reads of %d bytes: unexpected error: %v", n, err))
+		}
+		if !bytes.Equal(got, want) {
+			return Error(fmt.Sprintf("reads of %d bytes: output mismatch", n))
+		}
+	}
+
+	for off := int64(0); off < consumed; off++ {
+		fr := &Reader{R: bytes.NewReader(input), Err: ErrInjected, ErrOffset: off}
+		if _, err := ioutil.ReadAll(newReader(fr)); err != ErrInjected {
+			return Error(fmt.Sprintf("error injected at offset %d: got %v, want %v", off, err, ErrInjected))
+		}
+	}
+	return nil
+}
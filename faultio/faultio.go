@@ -0,0 +1,152 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Package faultio provides readers and writers that inject faults for testing.
+//
+// The Reader and Writer types wrap a data source or sink and simulate short
+// reads, partial writes, stalls, and mid-stream errors at chosen offsets.
+// CheckReader uses them to verify that a decompressor handles each fault.
+package faultio
+
+import "io"
+import "time"
+
+// Error is the wrapper type for errors specific to this library.
+type Error string
+
+func (e Error) Error() string { return "faultio: " + string(e) }
+
+var (
+	// ErrInjected is a convenient error value to inject.
+	ErrInjected error = Error("injected fault")
+)
+
+// Reader wraps R and injects faults according to its fields.
+// The zero value of each field disables that fault.
+type Reader struct {
+	R io.Reader // Underlying reader
+
+	// MaxRead is the maximum number of bytes returned by each Read.
+	MaxRead int
+
+	// Err is returned by every Read once ErrOffset bytes have been read.
+	Err       error
+	ErrOffset int64
+
+	// Stall is how long to sleep the first time StallOffset is reached.
+	Stall       time.Duration
+	StallOffset int64
+
+	Offset  int64               // Total number of bytes read from R
+	stalled bool                // Whether the stall already occurred
+	sleep   func(time.Duration) // Overridden by tests; nil means time.Sleep
+}
+
+func (fr *Reader) Read(buf []byte) (int, error) {
+	if fr.MaxRead > 0 && len(buf) > fr.MaxRead {
+		buf = buf[:fr.MaxRead]
+	}
+	if fr.Err != nil {
+		if fr.Offset >= fr.ErrOffset {
+			return 0, fr.Err
+		}
+		if rem := fr.ErrOffset - fr.Offset; int64(len(buf)) > rem {
+			buf = buf[:rem]
+		}
+	}
+	if fr.Stall > 0 && !fr.stalled {
+		if fr.Offset >= fr.StallOffset {
+			fr.stalled = true
+			doSleep(fr.sleep, fr.Stall)
+		} else if rem := fr.StallOffset - fr.Offset; int64(len(buf)) > rem {
+			buf = buf[:rem]
+		}
+	}
+
+	cnt, err := fr.R.Read(buf)
+	fr.Offset += int64(cnt)
+	return cnt, err
+}
+
+// ReadByte is provided so that decompressors do not wrap the Reader in a
+// bufio.Reader, which would hide short reads from them.
+func (fr *Reader) ReadByte() (byte, error) {
+	var buf [1]byte
+	for {
+		cnt, err := fr.Read(buf[:])
+		if cnt > 0 {
+			return buf[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Writer wraps W and injects faults according to its fields.
+// The zero value of each field disables that fault.
+type Writer struct {
+	W io.Writer // Underlying writer
+
+	// MaxWrite is the maximum number of bytes accepted by each Write.
+	// Writes larger than this are truncated and report io.ErrShortWrite.
+	MaxWrite int
+
+	// Err is returned by every Write once ErrOffset bytes have been written.
+	Err       error
+	ErrOffset int64
+
+	// Stall is how long to sleep the first time StallOffset is reached.
+	Stall       time.Duration
+	StallOffset int64
+
+	Offset  int64               // Total number of bytes written to W
+	stalled bool                // Whether the stall already occurred
+	sleep   func(time.Duration) // Overridden by tests; nil means time.Sleep
+}
+
+func (fw *Writer) Write(buf []byte) (int, error) {
+	var total int
+	for len(buf) > 0 {
+		chunk, errShort := buf, error(nil)
+		if fw.MaxWrite > 0 && len(chunk) > fw.MaxWrite-total {
+			chunk, errShort = chunk[:fw.MaxWrite-total], io.ErrShortWrite
+		}
+		if fw.Err != nil {
+			if fw.Offset >= fw.ErrOffset {
+				return total, fw.Err
+			}
+			if rem := fw.ErrOffset - fw.Offset; int64(len(chunk)) > rem {
+				chunk, errShort = chunk[:rem], nil
+			}
+		}
+		if fw.Stall > 0 && !fw.stalled {
+			if fw.Offset >= fw.StallOffset {
+				fw.stalled = true
+				doSleep(fw.sleep, fw.Stall)
+			} else if rem := fw.StallOffset - fw.Offset; int64(len(chunk)) > rem {
+				chunk, errShort = chunk[:rem], nil
+			}
+		}
+
+		cnt, err := fw.W.Write(chunk)
+		fw.Offset += int64(cnt)
+		total += cnt
+		if err != nil {
+			return total, err
+		}
+		if errShort != nil {
+			return total, errShort
+		}
+		buf = buf[cnt:]
+	}
+	return total, nil
+}
+
+func doSleep(sleep func(time.Duration), d time.Duration) {
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	sleep(d)
+}
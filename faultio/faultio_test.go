@@ -0,0 +1,213 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package faultio
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "reflect"
+import "strings"
+import "testing"
+import "time"
+import "github.com/dsnet/compress/brotli"
+import "github.com/dsnet/compress/flate"
+
+// stallRecorder records the offsets and durations of injected stalls.
+type stallRecorder struct {
+	offset  *int64 // Offset of the stream being stalled
+	offsets []int64
+	delays  []time.Duration
+}
+
+func (sr *stallRecorder) Sleep(d time.Duration) {
+	sr.offsets = append(sr.offsets, *sr.offset)
+	sr.delays = append(sr.delays, d)
+}
+
+func (sr *stallRecorder) Check(t *testing.T, i int, desc string, stall time.Duration, off int64) {
+	var wantOffsets []int64
+	var wantDelays []time.Duration
+	if stall > 0 {
+		wantOffsets, wantDelays = []int64{off}, []time.Duration{stall}
+	}
+	if !reflect.DeepEqual(sr.offsets, wantOffsets) || !reflect.DeepEqual(sr.delays, wantDelays) {
+		t.Errorf("test %d, %s\nstall mismatch: got %v at %v, want %v at %v", i, desc, sr.delays, sr.offsets, wantDelays, wantOffsets)
+	}
+}
+
+func TestReader(t *testing.T) {
+	const input = "The quick brown fox jumps over the lazy dog."
+
+	var vectors = []struct {
+		desc   string // Description of the test
+		rd     Reader // Reader configuration (R is set automatically)
+		output string // Expected output
+		reads  int    // Expected number of successful reads
+		err    error  // Expected error
+	}{{
+		desc:   "no faults",
+		output: input,
+		reads:  1,
+	}, {
+		desc:   "short reads",
+		rd:     Reader{MaxRead: 10},
+		output: input,
+		reads:  5,
+	}, {
+		desc:   "error at start",
+		rd:     Reader{Err: ErrInjected},
+		output: "",
+		err:    ErrInjected,
+	}, {
+		desc:   "error mid-stream",
+		rd:     Reader{Err: ErrInjected, ErrOffset: 15},
+		output: input[:15],
+		reads:  1,
+		err:    ErrInjected,
+	}, {
+		desc:   "short reads and error mid-stream",
+		rd:     Reader{MaxRead: 10, Err: ErrInjected, ErrOffset: 15},
+		output: input[:15],
+		reads:  2,
+		err:    ErrInjected,
+	}, {
+		desc:   "stall at start",
+		rd:     Reader{Stall: time.Second},
+		output: input,
+		reads:  1,
+	}, {
+		desc:   "stall mid-stream",
+		rd:     Reader{Stall: time.Second, StallOffset: 20},
+		output: input,
+		reads:  2,
+	}, {
+		desc:   "short reads and stall mid-stream",
+		rd:     Reader{MaxRead: 10, Stall: time.Second, StallOffset: 25},
+		output: input,
+		reads:  5,
+	}}
+
+	for i, v := range vectors {
+		rd := v.rd
+		rd.R = strings.NewReader(input)
+		sr := &stallRecorder{offset: &rd.Offset}
+		rd.sleep = sr.Sleep
+		var output []byte
+		var reads int
+		buf := make([]byte, 64)
+		var err error
+		for {
+			var cnt int
+			cnt, err = rd.Read(buf)
+			if cnt > 0 {
+				output = append(output, buf[:cnt]...)
+				reads++
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+
+		if err != v.err {
+			t.Errorf("test %d, %s\nerror mismatch: got %v, want %v", i, v.desc, err, v.err)
+		}
+		if string(output) != v.output {
+			t.Errorf("test %d, %s\noutput mismatch: got %q, want %q", i, v.desc, output, v.output)
+		}
+		if reads != v.reads {
+			t.Errorf("test %d, %s\nread count mismatch: got %d, want %d", i, v.desc, reads, v.reads)
+		}
+		sr.Check(t, i, v.desc, v.rd.Stall, v.rd.StallOffset)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	const input = "The quick brown fox jumps over the lazy dog."
+
+	var vectors = []struct {
+		desc   string // Description of the test
+		wr     Writer // Writer configuration (W is set automatically)
+		output string // Expected output
+		err    error  // Expected error
+	}{{
+		desc:   "no faults",
+		output: input,
+	}, {
+		desc:   "partial write",
+		wr:     Writer{MaxWrite: 10},
+		output: input[:10],
+		err:    io.ErrShortWrite,
+	}, {
+		desc:   "error mid-stream",
+		wr:     Writer{Err: ErrInjected, ErrOffset: 15},
+		output: input[:15],
+		err:    ErrInjected,
+	}, {
+		desc:   "partial write before error",
+		wr:     Writer{MaxWrite: 10, Err: ErrInjected, ErrOffset: 15},
+		output: input[:10],
+		err:    io.ErrShortWrite,
+	}, {
+		desc:   "partial write after stall",
+		wr:     Writer{MaxWrite: 30, Stall: time.Second, StallOffset: 20},
+		output: input[:30],
+		err:    io.ErrShortWrite,
+	}, {
+		desc:   "stall mid-stream",
+		wr:     Writer{Stall: time.Second, StallOffset: 20},
+		output: input,
+	}, {
+		desc:   "stall beyond end of write",
+		wr:     Writer{Stall: time.Second, StallOffset: 100},
+		output: input,
+	}}
+
+	for i, v := range vectors {
+		var buf bytes.Buffer
+		wr := v.wr
+		wr.W = &buf
+		sr := &stallRecorder{offset: &wr.Offset}
+		wr.sleep = sr.Sleep
+		cnt, err := wr.Write([]byte(input))
+
+		if err != v.err {
+			t.Errorf("test %d, %s\nerror mismatch: got %v, want %v", i, v.desc, err, v.err)
+		}
+		if buf.String() != v.output || cnt != len(v.output) {
+			t.Errorf("test %d, %s\noutput mismatch: got %q, want %q", i, v.desc, buf.String(), v.output)
+		}
+		if v.wr.StallOffset < int64(len(input)) {
+			sr.Check(t, i, v.desc, v.wr.Stall, v.wr.StallOffset)
+		} else {
+			sr.Check(t, i, v.desc, 0, 0)
+		}
+	}
+}
+
+func TestCheckReader(t *testing.T) {
+	var vectors = []struct {
+		file      string                    // Compressed test file
+		newReader func(io.Reader) io.Reader // Decompressor to check
+	}{
+		{"../flate/testdata/digits-best-1e4.fl", func(r io.Reader) io.Reader { return flate.NewReader(r) }},
+		{"../flate/testdata/twain-speed-1e4.fl", func(r io.Reader) io.Reader { return flate.NewReader(r) }},
+		{"../brotli/testdata/monkey.br", func(r io.Reader) io.Reader { return brotli.NewReader(r) }},
+		{"../brotli/testdata/ukkonooa.br", func(r io.Reader) io.Reader { return brotli.NewReader(r) }},
+		{"../brotli/testdata/quickfox.br", func(r io.Reader) io.Reader { return brotli.NewReader(r) }},
+	}
+
+	for i, v := range vectors {
+		input, err := ioutil.ReadFile(v.file)
+		if err != nil {
+			t.Fatalf("test %d, unexpected error: %v", i, err)
+		}
+		if err := CheckReader(v.newReader, input); err != nil {
+			t.Errorf("test %d, %s: %v", i, v.file, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Package safeio guards decompressors against decompression bombs.
+//
+// A decompression bomb is a small input that expands into an enormous amount
+// of output. The Reader in this package wraps any decompressor and limits both
+// the total number of bytes it may produce and the ratio of bytes produced to
+// compressed bytes consumed.
+package safeio
+
+// Error is the wrapper type for errors specific to this library.
+type Error string
+
+func (e Error) Error() string { return "safeio: " + string(e) }
+
+var (
+	ErrSizeLimit  error = Error("output size limit exceeded")
+	ErrRatioLimit error = Error("expansion ratio limit exceeded")
+)
+
+// Limits specifies the bounds enforced by a Reader.
+// A zero value for any field disables that check.
+type Limits struct {
+	// MaxSize is the maximum number of decompressed bytes that may be read.
+	MaxSize int64
+
+	// MaxRatio is the maximum ratio of decompressed bytes to compressed bytes.
+	//
+	// Since short inputs may legitimately have high ratios, this is only
+	// enforced once more than RatioSlack bytes have been decompressed.
+	MaxRatio   int64
+	RatioSlack int64
+}
+
+// DefaultLimits are reasonable limits for decompressing untrusted input.
+//
+// The ratio limit is comfortably above the maximum ratio of about 1032:1 that
+// the DEFLATE format can achieve, while the slack allows for short streams of
+// highly repetitive data in formats with larger ratios (such as Brotli).
+var DefaultLimits = Limits{
+	MaxSize:    1 << 30,
+	MaxRatio:   1 << 11,
+	RatioSlack: 1 << 20,
+}
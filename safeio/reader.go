@@ -0,0 +1,108 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package safeio
+
+import "io"
+import "bufio"
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// countReader counts the number of compressed bytes actually consumed by the
+// decompressor. It implements io.ByteReader so that decompressors in this
+// library do not wrap it in a bufio.Reader and read ahead of what they need.
+type countReader struct {
+	rd     byteReader
+	offset int64
+}
+
+func (cr *countReader) Read(buf []byte) (int, error) {
+	cnt, err := cr.rd.Read(buf)
+	cr.offset += int64(cnt)
+	return cnt, err
+}
+
+func (cr *countReader) ReadByte() (byte, error) {
+	c, err := cr.rd.ReadByte()
+	if err == nil {
+		cr.offset++
+	}
+	return c, err
+}
+
+type Reader struct {
+	InputOffset  int64 // Total number of bytes read from underlying io.Reader
+	OutputOffset int64 // Total number of bytes emitted from Read
+
+	rd  io.Reader   // Decompressor being guarded
+	cr  countReader // Compressed data source
+	lim Limits      // Limits to enforce
+	err error       // Persistent error
+}
+
+// NewReader creates a Reader that decompresses data from r using the
+// decompressor returned by newReader, enforcing the limits in lim.
+//
+// The newReader function is called exactly once with a reader that counts
+// how much compressed input is consumed.
+func NewReader(r io.Reader, newReader func(io.Reader) io.Reader, lim Limits) *Reader {
+	sr := &Reader{lim: lim}
+	if rr, ok := r.(byteReader); ok {
+		sr.cr.rd = rr
+	} else {
+		sr.cr.rd = bufio.NewReader(r)
+	}
+	sr.rd = newReader(&sr.cr)
+	return sr
+}
+
+func (sr *Reader) Read(buf []byte) (int, error) {
+	if sr.err != nil {
+		return 0, sr.err
+	}
+
+	// Read at most one byte beyond MaxSize to detect if it would be exceeded.
+	var remain int64 = -1
+	if sr.lim.MaxSize > 0 {
+		remain = sr.lim.MaxSize - sr.OutputOffset
+		if int64(len(buf))-1 > remain {
+			buf = buf[:remain+1]
+		}
+	}
+
+	cnt, err := sr.rd.Read(buf)
+	if remain >= 0 && int64(cnt) > remain {
+		cnt, err = int(remain), ErrSizeLimit
+	}
+	sr.InputOffset = sr.cr.offset
+	sr.OutputOffset += int64(cnt)
+
+	// Decompressors may return the final data together with io.EOF, so the
+	// ratio must be checked on every read that produces data.
+	ratioCheck := cnt > 0 && (err == nil || err == io.EOF)
+	if ratioCheck && sr.lim.MaxRatio > 0 && sr.OutputOffset > sr.lim.RatioSlack {
+		if sr.OutputOffset/sr.lim.MaxRatio > sr.InputOffset {
+			err = ErrRatioLimit
+		}
+	}
+	sr.err = err
+	return cnt, err
+}
+
+// Close closes the underlying decompressor if it implements io.Closer.
+func (sr *Reader) Close() error {
+	if c, ok := sr.rd.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	if sr.err == nil || sr.err == io.EOF || sr.err == io.ErrClosedPipe {
+		sr.err = io.ErrClosedPipe // Make sure future reads fail
+		return nil
+	}
+	return sr.err // Return the persistent error
+}
@@ -0,0 +1,132 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package safeio
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "math"
+import "testing"
+import "github.com/dsnet/compress/brotli"
+import "github.com/dsnet/compress/flate"
+
+func newFlate(r io.Reader) io.Reader  { return flate.NewReader(r) }
+func newBrotli(r io.Reader) io.Reader { return brotli.NewReader(r) }
+
+func TestReader(t *testing.T) {
+	var vectors = []struct {
+		desc      string                    // Description of the test
+		file      string                    // Compressed test file
+		newReader func(io.Reader) io.Reader // Decompressor to use
+		lim       Limits                    // Limits to enforce
+		outIdx    int64                     // Expected output offset after reading
+		err       error                     // Expected error
+	}{{
+		desc:      "no limits",
+		file:      "../brotli/testdata/zeros.br",
+		newReader: newBrotli,
+		outIdx:    262144,
+	}, {
+		desc:      "default limits",
+		file:      "../flate/testdata/twain-default-1e6.fl",
+		newReader: newFlate,
+		lim:       DefaultLimits,
+		outIdx:    1000000,
+	}, {
+		desc:      "output exactly at size limit",
+		file:      "../flate/testdata/twain-default-1e6.fl",
+		newReader: newFlate,
+		lim:       Limits{MaxSize: 1000000},
+		outIdx:    1000000,
+	}, {
+		desc:      "output exceeds size limit",
+		file:      "../flate/testdata/twain-default-1e6.fl",
+		newReader: newFlate,
+		lim:       Limits{MaxSize: 999999},
+		outIdx:    999999,
+		err:       ErrSizeLimit,
+	}, {
+		desc:      "maximum size limit",
+		file:      "../flate/testdata/twain-default-1e6.fl",
+		newReader: newFlate,
+		lim:       Limits{MaxSize: math.MaxInt64},
+		outIdx:    1000000,
+	}, {
+		desc:      "output exceeds ratio limit",
+		file:      "../brotli/testdata/zeros.br",
+		newReader: newBrotli,
+		lim:       Limits{MaxRatio: 1000},
+		err:       ErrRatioLimit,
+	}, {
+		desc:      "output within ratio slack",
+		file:      "../brotli/testdata/zeros.br",
+		newReader: newBrotli,
+		lim:       Limits{MaxRatio: 1000, RatioSlack: 1 << 18},
+		outIdx:    262144,
+	}, {
+		desc:      "highly repetitive input exceeds default ratio",
+		file:      "../brotli/testdata/quickfox_repeated.br",
+		newReader: newBrotli,
+		lim:       Limits{MaxRatio: DefaultLimits.MaxRatio},
+		err:       ErrRatioLimit,
+	}}
+
+	for i, v := range vectors {
+		input, err := ioutil.ReadFile(v.file)
+		if err != nil {
+			t.Fatalf("test %d, %s\nunexpected error: %v", i, v.desc, err)
+		}
+		rd := NewReader(bytes.NewReader(input), v.newReader, v.lim)
+		_, err = io.Copy(ioutil.Discard, rd)
+
+		if err != v.err {
+			t.Errorf("test %d, %s\nerror mismatch: got %v, want %v", i, v.desc, err, v.err)
+		}
+		if v.outIdx > 0 && rd.OutputOffset != v.outIdx {
+			t.Errorf("test %d, %s\noutput offset mismatch: got %d, want %d", i, v.desc, rd.OutputOffset, v.outIdx)
+		}
+		if v.err == nil && rd.InputOffset != int64(len(input)) {
+			t.Errorf("test %d, %s\ninput offset mismatch: got %d, want %d", i, v.desc, rd.InputOffset, len(input))
+		}
+		if err := rd.Close(); err != v.err {
+			t.Errorf("test %d, %s\nclose error mismatch: got %v, want %v", i, v.desc, err, v.err)
+		}
+	}
+}
+
+// eofReader is a decompressor that consumes a single input byte and then
+// returns all of its output together with io.EOF.
+type eofReader struct {
+	rd     io.Reader
+	output int
+}
+
+func (er *eofReader) Read(buf []byte) (int, error) {
+	if _, err := er.rd.Read(make([]byte, 1)); err != nil {
+		return 0, err
+	}
+	cnt := er.output
+	if cnt > len(buf) {
+		cnt = len(buf)
+	}
+	er.output -= cnt
+	return cnt, io.EOF
+}
+
+func TestReaderRatioEOF(t *testing.T) {
+	newReader := func(r io.Reader) io.Reader { return &eofReader{rd: r, output: 1 << 24} }
+	rd := NewReader(bytes.NewReader([]byte{0}), newReader, Limits{MaxRatio: 10})
+	buf := make([]byte, 1<<24)
+	cnt, err := rd.Read(buf)
+	if err != ErrRatioLimit {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrRatioLimit)
+	}
+	if cnt != 1<<24 {
+		t.Errorf("count mismatch: got %d, want %d", cnt, 1<<24)
+	}
+	if _, err := rd.Read(buf); err != ErrRatioLimit {
+		t.Errorf("persistent error mismatch: got %v, want %v", err, ErrRatioLimit)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+// Package throttle limits the throughput of compression streams.
+//
+// A Reader or Writer in this package passes data through unchanged, but
+// blocks as necessary to keep the data rate within the budget of a Bucket.
+// Wrapping the input or output of a compressor bounds how much CPU time a
+// background job can take away from more important work. Since a Bucket may
+// be shared, many streams can be limited by a single combined budget.
+package throttle
+
+import "sync"
+import "time"
+
+// Bucket is a token bucket where each token represents one byte.
+// It is safe for concurrent use by multiple goroutines.
+type Bucket struct {
+	rate  float64 // Number of tokens added per second
+	burst int     // Maximum number of tokens that may accumulate
+
+	mu     sync.Mutex
+	tokens float64   // Number of tokens currently available; may be negative
+	last   time.Time // Last time tokens were added
+
+	// These are only overridden by tests.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewBucket creates a Bucket that allows rate bytes per second on average
+// with bursts of up to burst bytes. The bucket starts out full.
+// If burst is not positive, then it defaults to rate.
+func NewBucket(rate, burst int) *Bucket {
+	if rate <= 0 {
+		panic("throttle: rate must be positive")
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	b := &Bucket{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: float64(burst),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+	b.last = b.now()
+	return b
+}
+
+// Burst reports the maximum number of bytes that may be passed to Wait.
+func (b *Bucket) Burst() int {
+	return b.burst
+}
+
+// Wait blocks until n bytes may be processed.
+//
+// This invariant must be kept: 0 <= n <= Burst()
+func (b *Bucket) Wait(n int) {
+	b.mu.Lock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+
+	// Reserve the tokens now so that concurrent callers queue up behind us.
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay > 0 {
+		b.sleep(delay)
+	}
+}
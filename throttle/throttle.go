@@ -0,0 +1,66 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package throttle
+
+import "io"
+
+type Reader struct {
+	rd io.Reader // Underlying reader
+	b  *Bucket   // Throughput budget
+}
+
+// NewReader creates a Reader that reads from r at a rate limited by b.
+func NewReader(r io.Reader, b *Bucket) *Reader {
+	return &Reader{rd: r, b: b}
+}
+
+func (tr *Reader) Read(buf []byte) (int, error) {
+	if len(buf) > tr.b.Burst() {
+		buf = buf[:tr.b.Burst()]
+	}
+	cnt, err := tr.rd.Read(buf)
+	tr.b.Wait(cnt)
+	return cnt, err
+}
+
+type Writer struct {
+	wr io.Writer // Underlying writer
+	b  *Bucket   // Throughput budget
+}
+
+// NewWriter creates a Writer that writes to w at a rate limited by b.
+//
+// Large writes are split into pieces no larger than the bucket's burst size.
+// Placed in front of a compressor, the compressor thus only ever sees small
+// bursts of data, each of which is typically compressed as a unit.
+func NewWriter(w io.Writer, b *Bucket) *Writer {
+	return &Writer{wr: w, b: b}
+}
+
+func (tw *Writer) Write(buf []byte) (int, error) {
+	var total int
+	for len(buf) > 0 {
+		chunk := buf
+		if len(chunk) > tw.b.Burst() {
+			chunk = chunk[:tw.b.Burst()]
+		}
+		tw.b.Wait(len(chunk))
+		cnt, err := tw.wr.Write(chunk)
+		total += cnt
+		if err != nil {
+			return total, err
+		}
+		buf = buf[cnt:]
+	}
+	return total, nil
+}
+
+// Close closes the underlying writer if it implements io.Closer.
+func (tw *Writer) Close() error {
+	if c, ok := tw.wr.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
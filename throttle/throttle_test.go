@@ -0,0 +1,112 @@
+// Copyright 2015, Joe Tsai. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE.md file.
+
+package throttle
+
+import "io"
+import "io/ioutil"
+import "bytes"
+import "strings"
+import "testing"
+import "time"
+
+// fakeClock is a clock that only advances when sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time            { return fc.now }
+func (fc *fakeClock) Sleep(delay time.Duration) { fc.now = fc.now.Add(delay) }
+
+func newTestBucket(rate, burst int) (*Bucket, *fakeClock) {
+	fc := &fakeClock{now: time.Unix(0, 0)}
+	b := NewBucket(rate, burst)
+	b.now, b.sleep = fc.Now, fc.Sleep
+	b.last = fc.now
+	return b, fc
+}
+
+func TestBucket(t *testing.T) {
+	var vectors = []struct {
+		rate, burst int           // Bucket parameters
+		waits       []int         // Sequence of calls to Wait
+		elapsed     time.Duration // Expected time after all calls to Wait
+	}{
+		{rate: 1000, burst: 1000, waits: []int{1000}, elapsed: 0},
+		{rate: 1000, burst: 1000, waits: []int{1000, 1000}, elapsed: time.Second},
+		{rate: 1000, burst: 100, waits: []int{100, 100, 100}, elapsed: 200 * time.Millisecond},
+		{rate: 1000, burst: 0, waits: []int{500, 500, 500, 500}, elapsed: time.Second},
+		{rate: 10, burst: 10, waits: []int{10, 0, 5}, elapsed: 500 * time.Millisecond},
+	}
+
+	for i, v := range vectors {
+		b, fc := newTestBucket(v.rate, v.burst)
+		for _, n := range v.waits {
+			b.Wait(n)
+		}
+		if got := fc.now.Sub(time.Unix(0, 0)); got != v.elapsed {
+			t.Errorf("test %d, elapsed time mismatch: got %v, want %v", i, got, v.elapsed)
+		}
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	b, fc := newTestBucket(1000, 1000)
+	b.Wait(1000)
+
+	// Idle time refills the bucket, but never beyond the burst size.
+	fc.now = fc.now.Add(time.Hour)
+	start := fc.now
+	b.Wait(1000)
+	b.Wait(500)
+	if got, want := fc.now.Sub(start), 500*time.Millisecond; got != want {
+		t.Errorf("elapsed time mismatch: got %v, want %v", got, want)
+	}
+}
+
+func TestReader(t *testing.T) {
+	const input = "The quick brown fox jumps over the lazy dog."
+
+	b, fc := newTestBucket(4, 4)
+	output, err := ioutil.ReadAll(NewReader(strings.NewReader(input), b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(output) != input {
+		t.Errorf("output mismatch: got %q, want %q", output, input)
+	}
+	if got, want := fc.now.Sub(time.Unix(0, 0)), 10*time.Second; got != want {
+		t.Errorf("elapsed time mismatch: got %v, want %v", got, want)
+	}
+}
+
+type recordWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (rw *recordWriter) Write(buf []byte) (int, error) {
+	rw.writes = append(rw.writes, len(buf))
+	return rw.Buffer.Write(buf)
+}
+
+func TestWriter(t *testing.T) {
+	const input = "The quick brown fox jumps over the lazy dog."
+
+	b, fc := newTestBucket(10, 10)
+	var rw recordWriter
+	cnt, err := io.WriteString(NewWriter(&rw, b), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cnt != len(input) || rw.String() != input {
+		t.Errorf("output mismatch: got %q, want %q", rw.String(), input)
+	}
+	if len(rw.writes) != 5 {
+		t.Errorf("write count mismatch: got %d, want %d", len(rw.writes), 5)
+	}
+	if got, want := fc.now.Sub(time.Unix(0, 0)), 3400*time.Millisecond; got != want {
+		t.Errorf("elapsed time mismatch: got %v, want %v", got, want)
+	}
+}